@@ -0,0 +1,254 @@
+package agents
+
+import (
+	"github.com/robbrit/econerra/fixedpoint"
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// A Path is an ordered production chain, e.g. {Wheat, Flour, Bread}, where
+// each good after the first is produced from the one before it according to
+// the InputGood/InputRatio recorded in that good's GoodInfo.
+type Path []goods.Good
+
+// An Arbitrageur is a MarketAgent that watches a configured set of
+// production chains for mispricing between adjacent goods. When the net
+// spread across a whole chain clears minSpreadRatio after an inventory
+// carry cost, it buys the upstream good and sells the downstream good at
+// every step of the chain at once, the same way a triangular arbitrageur
+// trades every leg of a currency cycle together.
+type Arbitrageur struct {
+	paths          []Path
+	minSpreadRatio float64
+	carryCost      float64
+	capitalLimit   market.Size
+
+	// position tracks this tick's net exposure per good so a cycle left
+	// half-filled after legs are cancelled (see legs below) can still be
+	// unwound on the next Act. tradeCycle clears it when it opens a new
+	// cycle; otherwise it's only brought back to zero by OnFill confirming a
+	// good is actually flat again - never by unwind itself, since Post
+	// doesn't fill synchronously on a batch doubleAuction and the fill (if
+	// any) is still pending when unwind returns.
+	position    map[goods.Good]float64
+	needsUnwind bool
+
+	// legs records the market and OrderID of every leg posted by the
+	// current cycle, so that if one leg reports OnUnfilled the rest can
+	// be cancelled immediately instead of left resting to fill at real
+	// market risk. A cancelled leg may itself have partially filled
+	// before the cancellation lands - whatever did fill is still caught
+	// by position and unwound on the next Act.
+	legs []arbitrageurLeg
+
+	// unwindOrders records the most recent flattening order posted per
+	// good, so a still-unfilled remainder can be cancelled before unwind
+	// replaces it with a fresh order sized to whatever's still open,
+	// instead of leaving both resting at once.
+	unwindOrders map[goods.Good]arbitrageurLeg
+}
+
+// An arbitrageurLeg is one order posted by tradeCycle, remembered so it can
+// be cancelled if another leg in the same cycle goes unfilled.
+type arbitrageurLeg struct {
+	market market.Market
+	id     market.OrderID
+}
+
+// NewArbitrageur creates an Arbitrageur that watches paths for mispricing.
+// minSpreadRatio is the minimum ratio of (downstream revenue / upstream
+// cost) required before a cycle is traded, carryCost is the fractional
+// cost of holding a path's intermediate inventory for one tick, and
+// capitalLimit caps how many units of each path's first good it risks per
+// cycle.
+func NewArbitrageur(paths []Path, minSpreadRatio, carryCost float64, capitalLimit market.Size) *Arbitrageur {
+	return &Arbitrageur{
+		paths:          paths,
+		minSpreadRatio: minSpreadRatio,
+		carryCost:      carryCost,
+		capitalLimit:   capitalLimit,
+		position:       map[goods.Good]float64{},
+		unwindOrders:   map[goods.Good]arbitrageurLeg{},
+	}
+}
+
+// Act evaluates every configured path and trades the best opportunity that
+// clears minSpreadRatio, unless the previous cycle left a leg unfilled, in
+// which case it unwinds that exposure instead of opening a new one.
+func (a *Arbitrageur) Act(p *Parameters) {
+	if a.needsUnwind {
+		a.unwind(p)
+		return
+	}
+
+	var bestPath Path
+	var bestRatios []float64
+	var bestReverse bool
+	best := a.minSpreadRatio
+
+	for _, path := range a.paths {
+		ratios, ok := chainRatios(p, path)
+		if !ok {
+			continue
+		}
+
+		first, last := path[0], path[len(path)-1]
+		firstMarket, lastMarket := p.Goods[first].Market, p.Goods[last].Market
+		carry := 1 - a.carryCost*float64(len(path)-1)
+
+		// Forward: buy the upstream good, sell the downstream good.
+		if ratio := lastMarket.Bid().Float64() * carry / (ratios[len(ratios)-1] * firstMarket.Ask().Float64()); ratio > best {
+			best, bestPath, bestRatios, bestReverse = ratio, path, ratios, false
+		}
+		// Reverse: buy the downstream good, sell the upstream good.
+		if ratio := firstMarket.Bid().Float64() * ratios[len(ratios)-1] * carry / lastMarket.Ask().Float64(); ratio > best {
+			best, bestPath, bestRatios, bestReverse = ratio, path, ratios, true
+		}
+	}
+
+	if bestPath == nil {
+		return
+	}
+
+	a.tradeCycle(p, bestPath, bestRatios, bestReverse)
+}
+
+// chainRatios returns, for each good in path, how many units of path[0]
+// are needed to produce one unit of that good. It reports false if path
+// isn't a valid chain under p (each good isn't produced from the one
+// before it).
+func chainRatios(p *Parameters, path Path) ([]float64, bool) {
+	if len(path) < 2 {
+		return nil, false
+	}
+
+	ratios := make([]float64, len(path))
+	ratios[0] = 1
+	for i := 1; i < len(path); i++ {
+		info := p.Goods[path[i]]
+		if !info.HasInput || info.InputGood != path[i-1] {
+			return nil, false
+		}
+		ratios[i] = ratios[i-1] * info.InputRatio
+	}
+	return ratios, true
+}
+
+// tradeCycle posts a buy/sell leg between every adjacent pair of goods in
+// path, sized so that a.capitalLimit units of path[0] flow through the
+// whole chain. Forward trades buy upstream and sell downstream; reverse
+// trades do the opposite.
+func (a *Arbitrageur) tradeCycle(p *Parameters, path Path, ratios []float64, reverse bool) {
+	a.position = map[goods.Good]float64{}
+	a.legs = nil
+	a.unwindOrders = map[goods.Good]arbitrageurLeg{}
+
+	qty := func(i int) market.Size {
+		return market.Size{Value: fixedpoint.FromFloat(a.capitalLimit.Float64() / ratios[i])}
+	}
+
+	for i := 0; i+1 < len(path); i++ {
+		upstream, downstream := path[i], path[i+1]
+		buyGood, sellGood := upstream, downstream
+		buySize, sellSize := qty(i), qty(i+1)
+		if reverse {
+			buyGood, sellGood = downstream, upstream
+			buySize, sellSize = qty(i+1), qty(i)
+		}
+
+		buyMarket, sellMarket := p.Goods[buyGood].Market, p.Goods[sellGood].Market
+		buyID := buyMarket.Post(&market.Order{
+			Price: buyMarket.Ask(),
+			Size:  buySize,
+			Side:  market.Buy,
+			Owner: a,
+		})
+		a.legs = append(a.legs, arbitrageurLeg{buyMarket, buyID})
+
+		sellID := sellMarket.Post(&market.Order{
+			Price: sellMarket.Bid(),
+			Size:  sellSize,
+			Side:  market.Sell,
+			Owner: a,
+		})
+		a.legs = append(a.legs, arbitrageurLeg{sellMarket, sellID})
+	}
+}
+
+// cancelLegs cancels every leg still resting from the most recent
+// tradeCycle. Legs that have already filled (in full, or the portion of a
+// partial fill) simply won't be found by Cancel.
+func (a *Arbitrageur) cancelLegs() {
+	for _, leg := range a.legs {
+		leg.market.Cancel(leg.id)
+	}
+	a.legs = nil
+}
+
+// unwind posts a flattening order, opposite to its sign, for every good in
+// a.position that isn't already flat. Posting doesn't fill synchronously on
+// a batch doubleAuction, so position and needsUnwind stay live - corrected
+// by OnFill as fills actually land - rather than being cleared here; unwind
+// simply runs again next Act for whatever's still open, cancelling and
+// replacing any unfilled remainder of its last attempt with a fresh order
+// sized to the current exposure instead of leaving both resting at once.
+func (a *Arbitrageur) unwind(p *Parameters) {
+	flat := true
+
+	for good, amount := range a.position {
+		if amount == 0 {
+			delete(a.unwindOrders, good)
+			continue
+		}
+		flat = false
+
+		side := market.Sell
+		if amount < 0 {
+			side, amount = market.Buy, -amount
+		}
+
+		if leg, ok := a.unwindOrders[good]; ok {
+			leg.market.Cancel(leg.id)
+		}
+
+		m := p.Goods[good].Market
+		price := m.Bid()
+		if side == market.Buy {
+			price = m.Ask()
+		}
+		id := m.Post(&market.Order{
+			Price: price,
+			Size:  market.Size{Value: fixedpoint.FromFloat(amount)},
+			Side:  side,
+			Owner: a,
+		})
+		a.unwindOrders[good] = arbitrageurLeg{m, id}
+	}
+
+	a.needsUnwind = !flat
+}
+
+// OnFill tracks net exposure per good so a partially filled cycle can be
+// unwound later.
+func (a *Arbitrageur) OnFill(good goods.Good, side market.Side, price market.Price, size market.Size) {
+	if side == market.Buy {
+		a.position[good] += size.Float64()
+	} else {
+		a.position[good] -= size.Float64()
+	}
+}
+
+// OnUnfilled enforces all-or-none: if any leg of the current cycle goes
+// unfilled, the rest of the cycle's legs are cancelled immediately instead
+// of being left resting to fill at real market risk. Whatever already
+// filled before the cancellation lands is unwound on the next Act.
+//
+// This only protects legs in markets that haven't reset yet this tick -
+// Simulation.Tick resets markets in a fixed order precisely so that's a
+// consistent set from one tick to the next, rather than the result of Go's
+// randomized map iteration, but a leg in a market that resets before the
+// one that goes unfilled will still have already matched.
+func (a *Arbitrageur) OnUnfilled(good goods.Good, side market.Side, size market.Size) {
+	a.cancelLegs()
+	a.needsUnwind = true
+}
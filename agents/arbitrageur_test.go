@@ -0,0 +1,172 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+type fakeAgent struct {
+	fillSize market.Size
+	fillSide market.Side
+}
+
+func (fa *fakeAgent) OnFill(g goods.Good, s market.Side, p market.Price, q market.Size) {
+	fa.fillSize = q
+	fa.fillSide = s
+}
+
+func (fa *fakeAgent) OnUnfilled(g goods.Good, s market.Side, q market.Size) {}
+
+func TestArbitrageurTradesMispricedChain(t *testing.T) {
+	wheatMarket := market.NewDoubleAuction(goods.Wheat)
+	flourMarket := market.NewDoubleAuction(goods.Flour)
+
+	// Wheat is cheap and Flour is rich relative to the 2:1 conversion
+	// ratio, so buying Wheat and selling Flour should clear comfortably
+	// above the minimum spread.
+	wheatSeller := &fakeAgent{}
+	flourBuyer := &fakeAgent{}
+	wheatMarket.Post(&market.Order{Price: market.NewPrice(10), Size: market.NewSize(100), Side: market.Sell, Owner: wheatSeller})
+	flourMarket.Post(&market.Order{Price: market.NewPrice(30), Size: market.NewSize(100), Side: market.Buy, Owner: flourBuyer})
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Wheat: {Market: wheatMarket},
+			goods.Flour: {Market: flourMarket, HasInput: true, InputGood: goods.Wheat, InputRatio: 2},
+		},
+	}
+
+	arb := NewArbitrageur([]Path{{goods.Wheat, goods.Flour}}, 1.1, 0, market.NewSize(20))
+	arb.Act(params)
+
+	wheatMarket.Reset()
+	flourMarket.Reset()
+
+	if wheatSeller.fillSize != market.NewSize(20) || wheatSeller.fillSide != market.Sell {
+		t.Errorf("wheat seller: got size %v side %v, want 20 sell", wheatSeller.fillSize, wheatSeller.fillSide)
+	}
+	if flourBuyer.fillSize != market.NewSize(10) || flourBuyer.fillSide != market.Buy {
+		t.Errorf("flour buyer: got size %v side %v, want 10 buy", flourBuyer.fillSize, flourBuyer.fillSide)
+	}
+}
+
+func TestArbitrageurCancelsRestingLegsOnUnfilled(t *testing.T) {
+	wheatMarket := market.NewDoubleAuction(goods.Wheat)
+	flourMarket := market.NewDoubleAuction(goods.Flour)
+
+	wheatSeller := &fakeAgent{}
+	flourBuyer := &fakeAgent{}
+	wheatMarket.Post(&market.Order{Price: market.NewPrice(10), Size: market.NewSize(100), Side: market.Sell, Owner: wheatSeller})
+	// Only enough flour demand to partially fill the sell leg, so that leg
+	// goes unfilled once this tick's flour market resets.
+	flourMarket.Post(&market.Order{Price: market.NewPrice(30), Size: market.NewSize(4), Side: market.Buy, Owner: flourBuyer})
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Wheat: {Market: wheatMarket},
+			goods.Flour: {Market: flourMarket, HasInput: true, InputGood: goods.Wheat, InputRatio: 2},
+		},
+	}
+
+	arb := NewArbitrageur([]Path{{goods.Wheat, goods.Flour}}, 1.1, 0, market.NewSize(20))
+	arb.Act(params)
+
+	// Reset the market whose leg goes unfilled first: its OnUnfilled
+	// callback should cancel the still-resting wheat leg before wheatMarket's
+	// own Reset would otherwise match it in full against wheatSeller.
+	flourMarket.Reset()
+	wheatMarket.Reset()
+
+	if wheatSeller.fillSize != (market.Size{}) {
+		t.Errorf("wheat leg should have been cancelled once the flour leg went unfilled, got fill size %v", wheatSeller.fillSize)
+	}
+}
+
+func TestArbitrageurUnwindTracksPartialFill(t *testing.T) {
+	flourMarket := market.NewDoubleAuction(goods.Flour)
+
+	// Only enough cover liquidity to fill half of a short position of 4.
+	seller := &fakeAgent{}
+	flourMarket.Post(&market.Order{Price: market.NewPrice(10), Size: market.NewSize(2), Side: market.Sell, Owner: seller})
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Flour: {Market: flourMarket},
+		},
+	}
+
+	arb := NewArbitrageur(nil, 1.1, 0, market.NewSize(20))
+	arb.position[goods.Flour] = -4
+	arb.needsUnwind = true
+
+	arb.Act(params)
+	flourMarket.Reset()
+
+	if arb.position[goods.Flour] != -2 {
+		t.Errorf("position after partial unwind fill: got %v, want -2 (still short 2)", arb.position[goods.Flour])
+	}
+	if !arb.needsUnwind {
+		t.Error("needsUnwind should still be true: the position wasn't flat before this Act, so the next Act must keep trying to flatten it")
+	}
+}
+
+func TestArbitrageurUnwindCancelsStaleOrderOnContinuousMarket(t *testing.T) {
+	// Unlike doubleAuction, a continuousMatcher leaves an unfilled GTC order
+	// resting across Reset, so a second unwind attempt with no cover
+	// liquidity at all must cancel the first attempt's order before posting
+	// a fresh one - otherwise both would rest at once and later liquidity
+	// could overfill the real exposure.
+	flourMarket := market.NewContinuousMatcher(goods.Flour)
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Flour: {Market: flourMarket},
+		},
+	}
+
+	arb := NewArbitrageur(nil, 1.1, 0, market.NewSize(20))
+	arb.position[goods.Flour] = -4
+
+	arb.unwind(params)
+	flourMarket.Reset()
+	// No fill landed (there's no counterparty liquidity at all), so the
+	// exposure is still -4 going into the second unwind attempt.
+	arb.position[goods.Flour] = -4
+	arb.unwind(params)
+	flourMarket.Reset()
+
+	bids, _ := flourMarket.Book(int(market.DepthFull))
+	var total market.Size
+	for _, level := range bids {
+		total = total.Add(level.Size)
+	}
+	if total != market.NewSize(4) {
+		t.Errorf("resting unwind buys: got total size %v across %d level(s), want a single 4-unit order - a stale unwind order wasn't cancelled before the replacement was posted", total, len(bids))
+	}
+}
+
+func TestArbitrageurSkipsBelowThreshold(t *testing.T) {
+	wheatMarket := market.NewDoubleAuction(goods.Wheat)
+	flourMarket := market.NewDoubleAuction(goods.Flour)
+
+	wheatMarket.Post(&market.Order{Price: market.NewPrice(10), Size: market.NewSize(100), Side: market.Sell, Owner: &fakeAgent{}})
+	flourMarket.Post(&market.Order{Price: market.NewPrice(20), Size: market.NewSize(100), Side: market.Buy, Owner: &fakeAgent{}})
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Wheat: {Market: wheatMarket},
+			goods.Flour: {Market: flourMarket, HasInput: true, InputGood: goods.Wheat, InputRatio: 2},
+		},
+	}
+
+	// Fair value for Flour given the ratio is 20, so there's no edge here.
+	arb := NewArbitrageur([]Path{{goods.Wheat, goods.Flour}}, 1.1, 0, market.NewSize(20))
+	arb.Act(params)
+
+	bids, _ := wheatMarket.Book(int(market.DepthFull))
+	if len(bids) != 0 {
+		t.Errorf("expected no wheat buy posted, got %v", bids)
+	}
+}
@@ -1,8 +1,10 @@
 package agents
 
 import (
+	"encoding/json"
 	"math"
 
+	"github.com/robbrit/econerra/fixedpoint"
 	"github.com/robbrit/econerra/goods"
 	"github.com/robbrit/econerra/market"
 )
@@ -23,11 +25,20 @@ type Firm struct {
 	salesMade market.Size
 	// How many sales this firm wanted to make last iteration.
 	targetSales market.Size
+
+	// IDs of this firm's currently resting wage and sale orders, so they
+	// can be cancelled before a replacement is posted. This matters under
+	// continuous matching, where an unfilled order would otherwise rest
+	// forever; under a batch double auction it's harmless, since Reset
+	// already clears the book every period and the old ID simply won't
+	// be found.
+	wageOrderID market.OrderID
+	saleOrderID market.OrderID
 }
 
 // NewFirm creates a new firm with the given production parameters.
 func NewFirm(goodProduced goods.Good, initialWage, initialPrice market.Price) *Firm {
-	return &Firm{goodProduced, initialWage, initialPrice, 0, 0, 0, 0}
+	return &Firm{goodProduced, initialWage, initialPrice, market.Size{}, market.Size{}, market.Size{}, market.Size{}, 0, 0}
 }
 
 // TargetWorkers gets the number of workers that this firm is trying to hire
@@ -40,15 +51,17 @@ func (f *Firm) Act(p *Parameters) {
 	f.adjustPrices(p)
 	// Step two: calculate profit maximizing values of quantity and labour based on calculated prices.
 	f.chooseTargets(p)
-	// Step three: send out orders to the markets.
-	f.placeOrders(p)
-	// Step four: before we start receiving fills for orders, clear the information we saved from
-	// last iteration.
+	// Step three: before we start receiving fills for orders, clear the information we saved from
+	// last iteration. This has to happen before placeOrders, not after: under continuous matching
+	// placeOrders' Post calls can fill synchronously, and those fills need to survive to be read by
+	// next iteration's steps one and two rather than being wiped out immediately afterwards.
 	f.reset()
+	// Step four: send out orders to the markets.
+	f.placeOrders(p)
 }
 
 func (f *Firm) adjustPrices(p *Parameters) {
-	if f.targetSales == 0 {
+	if f.targetSales.IsZero() {
 		// This will happen in the first iteration, no need to adjust from the initial prices.
 		return
 	}
@@ -56,26 +69,52 @@ func (f *Firm) adjustPrices(p *Parameters) {
 	goodInfo := p.Goods[f.goodProduced]
 
 	// First adjust the price.
-	if f.salesMade < f.targetSales {
+	if f.salesMade.Cmp(f.targetSales) < 0 {
 		// Didn't sell enough, reduce price.
-		f.price -= p.Increment
+		f.price = f.price.Sub(p.Increment)
 		// TODO(rob): A more intelligent agent would probably look at the market to make a
 		// decision.
-	} else if f.price <= goodInfo.Market.High() {
-		// Made enough sales, bump up price to the maximum of what was selling last round.
-		f.price = goodInfo.Market.High() + p.Increment
+	} else if f.price.Cmp(goodInfo.Market.High()) <= 0 {
+		// Made enough sales - bump the price up to whatever the book says would still
+		// clear our target volume against resting demand. The current book is often
+		// empty at this point in the tick (Reset clears it at the end of every prior
+		// period, and this firm may be the first or only participant to Act this
+		// tick), so fall back to last period's high instead of collapsing to zero.
+		bids, _ := goodInfo.Market.Book(int(market.DepthMedium))
+		f.price = clearingPrice(bids, f.targetSales, goodInfo.Market.High()).Add(p.Increment)
 	}
 
 	// Now adjust the wage.
-	if f.workersHired < f.targetWorkers {
-		// Didn't hire enough people, offer a better wage than the market.
-		f.wage = p.LabourMarket.High() + p.Increment
-	} else if f.wage >= p.LabourMarket.Low() {
+	if f.workersHired.Cmp(f.targetWorkers) < 0 {
+		// Didn't hire enough people - offer whatever the book says would clear our
+		// target number of workers against resting labour supply, falling back to
+		// last period's low for the same reason as above.
+		_, asks := p.LabourMarket.Book(int(market.DepthMedium))
+		f.wage = clearingPrice(asks, f.targetWorkers, p.LabourMarket.Low()).Add(p.Increment)
+	} else if f.wage.Cmp(p.LabourMarket.Low()) >= 0 {
 		// Got enough people, lower wages if possible
 		// TODO(rob): A more intelligent agent would probably look at the market to make a
 		// decision.
-		f.wage -= p.Increment
+		f.wage = f.wage.Sub(p.Increment)
+	}
+}
+
+// clearingPrice walks levels, which must be sorted best price first, and
+// returns the price at which cumulative size would reach target. If the
+// levels don't hold enough size to reach target, it returns the worst price
+// available; if levels is empty, it returns fallback instead.
+func clearingPrice(levels []market.Level, target market.Size, fallback market.Price) market.Price {
+	var cum market.Size
+	for _, l := range levels {
+		cum = cum.Add(l.Size)
+		if cum.Cmp(target) >= 0 {
+			return l.Price
+		}
 	}
+	if len(levels) > 0 {
+		return levels[len(levels)-1].Price
+	}
+	return fallback
 }
 
 func (f *Firm) chooseTargets(p *Parameters) {
@@ -90,38 +129,54 @@ func (f *Firm) chooseTargets(p *Parameters) {
 
 		   L = (wage / (price * scale * tech))^(1 / (scale - 1))
 
+		Labour is no longer restricted to whole units - a continuous optimum is
+		truncated to the labour market's lot size so it can be posted directly.
 	*/
-	base := float64(f.wage) / (float64(f.price) * goodInfo.Tech * goodInfo.Scale)
+	base := f.wage.Float64() / (f.price.Float64() * goodInfo.Tech * goodInfo.Scale)
 	exp := 1.0 / (goodInfo.Scale - 1.0)
 	targetLabour := math.Pow(base, exp)
 
-	// Since labour is discrete, need to see which of the ceiling or floor gives better profits.
-	if f.profits(p, math.Ceil(targetLabour)) > f.profits(p, math.Floor(targetLabour)) {
-		f.targetWorkers = market.Size(math.Ceil(targetLabour))
-	} else {
-		f.targetWorkers = market.Size(math.Floor(targetLabour))
-	}
+	f.targetWorkers = market.Size{Value: fixedpoint.FromFloat(targetLabour)}.TruncateQuantity(market.Size{Value: p.LabourMarket.Good().Info().LotSize})
 
-	if f.workersHired > 0 {
+	if !f.workersHired.IsZero() {
 		// Can only produce if we managed to hire workers last iteration.
 		// Note that this will produce a lag between prices and wages.
-		f.targetSales = market.Size(math.Floor(f.production(p, float64(f.workersHired))))
+		f.targetSales = market.Size{Value: fixedpoint.FromFloat(f.production(p, f.workersHired.Float64()))}.TruncateQuantity(market.Size{Value: goodInfo.Market.Good().Info().LotSize})
 	}
 }
 
 func (f *Firm) placeOrders(p *Parameters) {
-	if f.targetWorkers > 0 {
-		p.LabourMarket.Post(&market.Order{
+	f.placeLabourOrder(p)
+	f.placeSaleOrder(p)
+}
+
+// placeLabourOrder cancels the firm's previous wage order, if any, and
+// posts a new one for this period's target labour.
+func (f *Firm) placeLabourOrder(p *Parameters) {
+	if f.wageOrderID != 0 {
+		p.LabourMarket.Cancel(f.wageOrderID)
+		f.wageOrderID = 0
+	}
+	if !f.targetWorkers.IsZero() {
+		f.wageOrderID = p.LabourMarket.Post(&market.Order{
 			Price: f.wage,
 			Size:  f.targetWorkers,
 			Side:  market.Buy,
 			Owner: f,
 		})
 	}
+}
 
-	if f.targetSales > 0 {
-		goodInfo := p.Goods[f.goodProduced]
-		goodInfo.Market.Post(&market.Order{
+// placeSaleOrder cancels the firm's previous sale order, if any, and posts
+// a new one for this period's target sales.
+func (f *Firm) placeSaleOrder(p *Parameters) {
+	goodInfo := p.Goods[f.goodProduced]
+	if f.saleOrderID != 0 {
+		goodInfo.Market.Cancel(f.saleOrderID)
+		f.saleOrderID = 0
+	}
+	if !f.targetSales.IsZero() {
+		f.saleOrderID = goodInfo.Market.Post(&market.Order{
 			Price: f.price,
 			Size:  f.targetSales,
 			Side:  market.Sell,
@@ -131,17 +186,8 @@ func (f *Firm) placeOrders(p *Parameters) {
 }
 
 func (f *Firm) reset() {
-	f.workersHired = 0
-	f.salesMade = 0
-}
-
-// profits calculates how much profit a firm makes given a wage and target labour.
-// Note that this is expected profits - it's possible the firm will not sell all the goods it
-// produces.
-func (f *Firm) profits(p *Parameters, labour float64) float64 {
-	wage := float64(f.wage)
-	price := float64(f.price)
-	return price*f.production(p, labour) - float64(wage)*labour
+	f.workersHired = market.Size{}
+	f.salesMade = market.Size{}
 }
 
 // production calculates how much the firm produces with a given amount of labour.
@@ -150,12 +196,50 @@ func (f *Firm) production(p *Parameters, labour float64) float64 {
 	return goodInfo.Tech * math.Pow(labour, goodInfo.Scale)
 }
 
+// firmState is the serializable snapshot of a Firm.
+type firmState struct {
+	Wage, Price                 market.Price
+	WorkersHired, TargetWorkers market.Size
+	SalesMade, TargetSales      market.Size
+	WageOrderID, SaleOrderID    market.OrderID
+}
+
+// MarshalState serializes the firm's pricing and hiring state so a
+// simulation can be checkpointed and later restored.
+func (f *Firm) MarshalState() ([]byte, error) {
+	return json.Marshal(firmState{
+		Wage:          f.wage,
+		Price:         f.price,
+		WorkersHired:  f.workersHired,
+		TargetWorkers: f.targetWorkers,
+		SalesMade:     f.salesMade,
+		TargetSales:   f.targetSales,
+		WageOrderID:   f.wageOrderID,
+		SaleOrderID:   f.saleOrderID,
+	})
+}
+
+// UnmarshalState restores state previously produced by MarshalState. The
+// good this firm produces is not part of the snapshot - it's fixed at
+// construction and is the caller's responsibility to match up.
+func (f *Firm) UnmarshalState(data []byte) error {
+	var s firmState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	f.wage, f.price = s.Wage, s.Price
+	f.workersHired, f.targetWorkers = s.WorkersHired, s.TargetWorkers
+	f.salesMade, f.targetSales = s.SalesMade, s.TargetSales
+	f.wageOrderID, f.saleOrderID = s.WageOrderID, s.SaleOrderID
+	return nil
+}
+
 // OnFill is triggered when the firm makes a sale.
 func (f *Firm) OnFill(good goods.Good, side market.Side, wage market.Price, size market.Size) {
 	if good == goods.Labour {
-		f.workersHired += size
+		f.workersHired = f.workersHired.Add(size)
 	} else if good == f.goodProduced {
-		f.salesMade += size
+		f.salesMade = f.salesMade.Add(size)
 	}
 }
 
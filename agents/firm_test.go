@@ -0,0 +1,90 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// continuousCounterparty supplies cheap labour and buys the firm's output
+// every tick, giving the firm under test someone to trade with under
+// continuous matching.
+type continuousCounterparty struct{}
+
+func (continuousCounterparty) OnFill(goods.Good, market.Side, market.Price, market.Size) {}
+func (continuousCounterparty) OnUnfilled(goods.Good, market.Side, market.Size)           {}
+
+// TestFirmProducesUnderContinuousMatching guards against a regression where
+// a firm's own same-tick fills (delivered synchronously by a continuous
+// matcher) were wiped out before the next Act() could read them, leaving
+// the firm unable to ever learn it had hired anyone and so never producing.
+func TestFirmProducesUnderContinuousMatching(t *testing.T) {
+	labourMarket := market.NewContinuousMatcher(goods.Labour)
+	wheatMarket := market.NewContinuousMatcher(goods.Wheat)
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Wheat: {Market: wheatMarket, Tech: 2, Scale: 0.5},
+		},
+		LabourMarket: labourMarket,
+		Increment:    market.NewPrice(1),
+	}
+
+	firm := NewFirm(goods.Wheat, market.NewPrice(10), market.NewPrice(20))
+	cp := &continuousCounterparty{}
+
+	for i := 0; i < 3; i++ {
+		labourMarket.Post(&market.Order{Price: market.NewPrice(5), Size: market.NewSize(5), Side: market.Sell, Owner: cp})
+		wheatMarket.Post(&market.Order{Price: market.NewPrice(25), Size: market.NewSize(5), Side: market.Buy, Owner: cp})
+		firm.Act(params)
+	}
+
+	if firm.TargetWorkers().IsZero() {
+		t.Fatal("firm should have a nonzero labour target once it's trading")
+	}
+
+	_, asks := wheatMarket.Book(int(market.DepthFull))
+	if len(asks) == 0 {
+		t.Error("firm should have posted a wheat sell order within a few ticks, having hired workers synchronously along the way")
+	}
+}
+
+// TestFirmPriceDoesNotCollapseOnFullClear guards against a regression where
+// adjustPrices' clearingPrice fallback returned a bare zero Price when the
+// current period's book was empty - which it always is right when a firm's
+// own Act runs, since Reset clears the book at the end of every prior tick.
+// A firm that fully sold its target one period would then see its price
+// collapse to essentially Increment the very next period instead of holding
+// near last period's High().
+func TestFirmPriceDoesNotCollapseOnFullClear(t *testing.T) {
+	labourMarket := market.NewDoubleAuction(goods.Labour)
+	wheatMarket := market.NewDoubleAuction(goods.Wheat)
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Wheat: {Market: wheatMarket, Tech: 2, Scale: 0.5},
+		},
+		LabourMarket: labourMarket,
+		Increment:    market.NewPrice(1),
+	}
+
+	firm := NewFirm(goods.Wheat, market.NewPrice(10), market.NewPrice(20))
+	cp := &continuousCounterparty{}
+
+	// Liquidity is posted after Act, not before: this mirrors Simulation.Tick,
+	// which runs every firm's Act before any market Reset, so the book this
+	// firm's own adjustPrices sees is whatever was posted so far THIS tick -
+	// nothing, in a market with no other agent posting ahead of Act.
+	for i := 0; i < 4; i++ {
+		firm.Act(params)
+		labourMarket.Post(&market.Order{Price: market.NewPrice(5), Size: market.NewSize(100), Side: market.Sell, Owner: cp})
+		wheatMarket.Post(&market.Order{Price: market.NewPrice(25), Size: market.NewSize(100), Side: market.Buy, Owner: cp})
+		labourMarket.Reset()
+		wheatMarket.Reset()
+	}
+
+	if firm.price.Cmp(market.NewPrice(15)) < 0 {
+		t.Errorf("price collapsed after fully clearing sales target: got %s, want something near last period's high", firm.price.String())
+	}
+}
@@ -0,0 +1,112 @@
+package agents
+
+import (
+	"github.com/robbrit/econerra/fixedpoint"
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// A LadderingFirm is a Firm that splits its sale order across several
+// descending price rungs instead of resting it all at a single price - a
+// laddered sell wall that fills the best price first and only gives up
+// ground as demand eats through it.
+type LadderingFirm struct {
+	*Firm
+
+	// Levels is how many price rungs the sale ladder is split across.
+	Levels int
+	// Deviation is the fractional price step between adjacent rungs: rung k
+	// (0-indexed) prices at price * (1 - k*Deviation).
+	Deviation float64
+
+	saleGroupID  market.GroupID
+	lastUnfilled market.Size
+}
+
+// NewLadderingFirm creates a new LadderingFirm with the given production
+// parameters and ladder shape.
+func NewLadderingFirm(goodProduced goods.Good, initialWage, initialPrice market.Price, levels int, deviation float64) *LadderingFirm {
+	return &LadderingFirm{
+		Firm:      NewFirm(goodProduced, initialWage, initialPrice),
+		Levels:    levels,
+		Deviation: deviation,
+	}
+}
+
+// Act triggers the firm's decision process, laddering its sale order across
+// Levels price rungs instead of resting it all at a single price.
+func (f *LadderingFirm) Act(p *Parameters) {
+	f.adjustPrices(p)
+	f.chooseTargets(p)
+	f.reset()
+	f.placeLabourOrder(p)
+	f.placeSaleLadder(p)
+}
+
+// placeSaleLadder cancels the firm's previous sale ladder, if any, and posts
+// a new one splitting targetSales across Levels descending price rungs. If
+// the previous ladder left size unfilled, the deviation between rungs is
+// halved so the firm competes harder for the remaining demand.
+func (f *LadderingFirm) placeSaleLadder(p *Parameters) {
+	goodInfo := p.Goods[f.goodProduced]
+	if f.saleGroupID != 0 {
+		goodInfo.Market.CancelGroup(f.saleGroupID)
+		f.saleGroupID = 0
+	}
+
+	unfilled := f.lastUnfilled
+	f.lastUnfilled = market.Size{}
+
+	if f.targetSales.IsZero() {
+		return
+	}
+
+	deviation := f.Deviation
+	if !unfilled.IsZero() {
+		deviation /= 2
+	}
+
+	lotSize := market.Size{Value: goodInfo.Market.Good().Info().LotSize}
+	tickSize := market.Price{Value: goodInfo.Market.Good().Info().TickSize}
+
+	levelSize := f.targetSales.Div(market.NewSize(int64(f.Levels))).TruncateQuantity(lotSize)
+	placements := make([]market.Placement, f.Levels)
+	var allocated market.Size
+	for k := 0; k < f.Levels; k++ {
+		size := levelSize
+		if k == f.Levels-1 {
+			// Give the last rung whatever truncation left over, so the
+			// ladder's total exactly matches targetSales.
+			size = f.targetSales.Sub(allocated)
+		}
+		allocated = allocated.Add(size)
+
+		factor := 1 - float64(k)*deviation
+		if factor < 0 {
+			// A deep rung with a large Levels/Deviation combination would
+			// otherwise price at zero or below - floor it at a single tick.
+			factor = 0
+		}
+		price := market.Price{Value: fixedpoint.FromFloat(f.price.Float64() * factor)}.TruncatePrice(tickSize)
+		if price.Cmp(tickSize) < 0 {
+			price = tickSize
+		}
+		placements[k] = market.Placement{Price: price, Size: size}
+	}
+
+	f.saleGroupID = goodInfo.Market.PostGroup(&market.OrderGroup{
+		Placements: placements,
+		Side:       market.Sell,
+		Owner:      f,
+	})
+}
+
+// OnUnfilled records the combined remaining size of the firm's sale ladder,
+// so placeSaleLadder can tighten the rung spacing next period when the
+// ladder didn't fully clear. Labour unfilled events fall through to the
+// embedded Firm, which has nothing to do with them either.
+func (f *LadderingFirm) OnUnfilled(good goods.Good, side market.Side, size market.Size) {
+	if good == f.goodProduced && side == market.Sell {
+		f.lastUnfilled = size
+	}
+}
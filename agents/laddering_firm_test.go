@@ -0,0 +1,73 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// TestLadderingFirmPostsMultipleLevels checks that a LadderingFirm splits
+// its target sales across Levels descending price rungs rather than resting
+// it all at a single price.
+func TestLadderingFirmPostsMultipleLevels(t *testing.T) {
+	labourMarket := market.NewDoubleAuction(goods.Labour)
+	wheatMarket := market.NewDoubleAuction(goods.Wheat)
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Wheat: {Market: wheatMarket, Tech: 2, Scale: 0.5},
+		},
+		LabourMarket: labourMarket,
+		Increment:    market.NewPrice(1),
+	}
+
+	firm := NewLadderingFirm(goods.Wheat, market.NewPrice(10), market.NewPrice(20), 4, 0.05)
+	cp := &continuousCounterparty{}
+	labourMarket.Post(&market.Order{Price: market.NewPrice(5), Size: market.NewSize(5), Side: market.Sell, Owner: cp})
+
+	firm.Act(params)
+	labourMarket.Reset()
+	wheatMarket.Reset()
+	firm.Act(params)
+
+	_, asks := wheatMarket.Book(int(market.DepthFull))
+	if len(asks) <= 1 {
+		t.Fatalf("expected sell ladder to span multiple price levels, got %d", len(asks))
+	}
+}
+
+// TestLadderingFirmTightensAfterUnfilled checks that a ladder left partly
+// unfilled at the end of a period gets a tighter rung spacing next time,
+// via the OnUnfilled callback feeding back into placeSaleLadder.
+func TestLadderingFirmTightensAfterUnfilled(t *testing.T) {
+	wheatMarket := market.NewDoubleAuction(goods.Wheat)
+	labourMarket := market.NewDoubleAuction(goods.Labour)
+
+	params := &Parameters{
+		Goods: map[goods.Good]GoodInfo{
+			goods.Wheat: {Market: wheatMarket, Tech: 2, Scale: 0.5},
+		},
+		LabourMarket: labourMarket,
+		Increment:    market.NewPrice(1),
+	}
+
+	firm := NewLadderingFirm(goods.Wheat, market.NewPrice(10), market.NewPrice(20), 3, 0.1)
+	firm.targetSales = market.NewSize(9)
+	firm.workersHired = market.NewSize(1)
+
+	firm.placeSaleLadder(params)
+	wheatMarket.Reset() // nothing resting to buy it, so the whole ladder expires unfilled
+
+	if firm.lastUnfilled.IsZero() {
+		t.Fatal("expected OnUnfilled to record the ladder's unfilled remainder")
+	}
+
+	// The first ladder priced its rungs at 20/18/16; with the deviation
+	// halved to 0.05 the re-posted ladder should price them at 20/19/18.
+	firm.placeSaleLadder(params)
+	_, asks := wheatMarket.Book(int(market.DepthFull))
+	if len(asks) != 3 || asks[0].Price != market.NewPrice(18) {
+		t.Errorf("expected the re-posted ladder's rungs at 20/19/18, got %v", asks)
+	}
+}
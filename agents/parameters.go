@@ -0,0 +1,39 @@
+package agents
+
+import (
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+)
+
+// GoodInfo describes how a good is produced and where it trades.
+type GoodInfo struct {
+	// Market is where this good is bought and sold.
+	Market market.Market
+	// Tech is the total factor productivity in the Cobb-Douglas production
+	// function Q = Tech * L^Scale.
+	Tech float64
+	// Scale is the returns-to-labour exponent in the Cobb-Douglas
+	// production function.
+	Scale float64
+
+	// HasInput reports whether this good is produced from another good
+	// rather than from Labour alone, e.g. Flour being produced from Wheat.
+	HasInput bool
+	// InputGood is the good this good is produced from, meaningful only
+	// when HasInput is true.
+	InputGood goods.Good
+	// InputRatio is how many units of InputGood are required to produce
+	// one unit of this good, meaningful only when HasInput is true.
+	InputRatio float64
+}
+
+// Parameters holds the shared simulation state that agents need in order
+// to act: where to trade, and how production works.
+type Parameters struct {
+	// Goods maps each produced good to its production and market info.
+	Goods map[goods.Good]GoodInfo
+	// LabourMarket is where firms hire workers.
+	LabourMarket market.Market
+	// Increment is the step size firms use when adjusting wages and prices.
+	Increment market.Price
+}
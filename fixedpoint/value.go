@@ -0,0 +1,127 @@
+// Package fixedpoint provides a fixed-point decimal value type for
+// representing quantities that must avoid the rounding surprises of
+// float64 (prices, sizes, balances) while remaining cheap to copy and
+// compare, following the same pattern used by most trading bots.
+package fixedpoint
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// scale is the number of decimal places a Value can represent. Internally a
+// Value is stored as an int64 number of 1/scaleFactor units.
+const scale = 8
+
+var scaleFactor = int64(math.Pow10(scale))
+
+// A Value is a fixed-point decimal number with 8 decimal places of
+// precision, stored as an int64 count of 1e-8 units.
+type Value struct {
+	ticks int64
+}
+
+// Zero is the additive identity.
+var Zero = Value{}
+
+// FromInt creates a Value representing the integer i.
+func FromInt(i int64) Value {
+	return Value{ticks: i * scaleFactor}
+}
+
+// FromFloat creates a Value from a float64. Because float64 can't represent
+// every decimal exactly, prefer FromInt or Parse when the exact value
+// matters.
+func FromFloat(f float64) Value {
+	return Value{ticks: int64(math.Round(f * float64(scaleFactor)))}
+}
+
+// Parse parses a decimal string such as "1.25" into a Value.
+func Parse(s string) (Value, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Value{}, fmt.Errorf("fixedpoint: invalid value %q: %w", s, err)
+	}
+	return FromFloat(f), nil
+}
+
+// Add returns v + other.
+func (v Value) Add(other Value) Value {
+	return Value{ticks: v.ticks + other.ticks}
+}
+
+// Sub returns v - other.
+func (v Value) Sub(other Value) Value {
+	return Value{ticks: v.ticks - other.ticks}
+}
+
+// Mul returns v * other.
+func (v Value) Mul(other Value) Value {
+	// Both operands are scaled by scaleFactor, so the naive product is
+	// scaled by scaleFactor^2 - divide back down to a single scale.
+	return Value{ticks: int64(math.Round(float64(v.ticks) * float64(other.ticks) / float64(scaleFactor)))}
+}
+
+// Div returns v / other. Dividing by zero returns Zero.
+func (v Value) Div(other Value) Value {
+	if other.ticks == 0 {
+		return Zero
+	}
+	return Value{ticks: int64(math.Round(float64(v.ticks) * float64(scaleFactor) / float64(other.ticks)))}
+}
+
+// Cmp compares v to other, returning -1, 0, or 1 if v is less than, equal
+// to, or greater than other.
+func (v Value) Cmp(other Value) int {
+	switch {
+	case v.ticks < other.ticks:
+		return -1
+	case v.ticks > other.ticks:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero reports whether v is the zero value.
+func (v Value) IsZero() bool { return v.ticks == 0 }
+
+// Truncate rounds v down towards zero to the nearest multiple of step. A
+// zero or negative step is treated as "no truncation".
+func (v Value) Truncate(step Value) Value {
+	if step.ticks <= 0 {
+		return v
+	}
+	return Value{ticks: (v.ticks / step.ticks) * step.ticks}
+}
+
+// Float64 converts v to a float64, which may lose precision.
+func (v Value) Float64() float64 {
+	return float64(v.ticks) / float64(scaleFactor)
+}
+
+// String renders v as a decimal string, trimming trailing zeros.
+func (v Value) String() string {
+	s := strconv.FormatFloat(v.Float64(), 'f', -1, 64)
+	return s
+}
+
+// MarshalJSON renders v as a JSON number.
+func (v Value) MarshalJSON() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalJSON parses v from a JSON number or string.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
@@ -0,0 +1,45 @@
+// Package goods enumerates the tradeable commodities in the simulation and
+// the per-good trading metadata (tick size, lot size) that markets enforce.
+package goods
+
+import "github.com/robbrit/econerra/fixedpoint"
+
+// A Good identifies a tradeable commodity.
+//
+//go:generate stringer -type=Good
+type Good uint8
+
+const (
+	// Labour is the good that firms buy from households to produce things.
+	Labour Good = iota
+	// Wheat is a raw agricultural good.
+	Wheat
+	// Flour is produced from Wheat.
+	Flour
+	// Bread is produced from Flour.
+	Bread
+)
+
+// Info carries the per-good metadata that markets need in order to enforce
+// valid order sizes and prices.
+type Info struct {
+	// Name is the human-readable name of the good.
+	Name string
+	// TickSize is the smallest price increment this good can trade at.
+	TickSize fixedpoint.Value
+	// LotSize is the smallest size increment this good can trade in.
+	LotSize fixedpoint.Value
+}
+
+var infos = map[Good]Info{
+	Labour: {Name: "Labour", TickSize: fixedpoint.FromFloat(0.01), LotSize: fixedpoint.FromFloat(0.01)},
+	Wheat:  {Name: "Wheat", TickSize: fixedpoint.FromFloat(0.01), LotSize: fixedpoint.FromFloat(0.01)},
+	Flour:  {Name: "Flour", TickSize: fixedpoint.FromFloat(0.01), LotSize: fixedpoint.FromFloat(0.01)},
+	Bread:  {Name: "Bread", TickSize: fixedpoint.FromFloat(0.01), LotSize: fixedpoint.FromFloat(0.01)},
+}
+
+// Info returns the trading metadata for g. Goods that have not been
+// registered get the zero Info, which imposes no tick or lot size.
+func (g Good) Info() Info {
+	return infos[g]
+}
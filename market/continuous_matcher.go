@@ -0,0 +1,325 @@
+package market
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+// continuousMatcher matches each incoming order against the resting book
+// immediately, in price-time priority, instead of waiting for Reset to
+// batch everything together. Reset still marks the end of a trading
+// period: it rolls the high/low/volume stats and expires any GTT orders,
+// but otherwise leaves the resting GTC book untouched.
+type continuousMatcher struct {
+	good goods.Good
+	info goods.Info
+
+	nextID      OrderID
+	nextGroupID GroupID
+	buys        []*resting
+	sells       []*resting
+
+	traded    bool
+	high, low Price
+	volume    Size
+}
+
+// NewContinuousMatcher creates a Market that matches orders against the
+// resting book as soon as they're posted, firing OnFill synchronously.
+func NewContinuousMatcher(good goods.Good) Market {
+	return &continuousMatcher{
+		good: good,
+		info: good.Info(),
+	}
+}
+
+// Post truncates o to the good's tick/lot size, matches it immediately
+// against the resting book, and - for GTC and GTT orders - rests whatever
+// didn't fill. IOC orders discard any unfilled remainder instead of
+// resting, and FOK orders are matched in full or not at all.
+func (m *continuousMatcher) Post(o *Order) OrderID {
+	o.Price = o.Price.TruncatePrice(Price{m.info.TickSize})
+	o.Size = o.Size.TruncateQuantity(Size{m.info.LotSize})
+
+	m.nextID++
+	r := &resting{id: m.nextID, order: o, remaining: o.Size}
+
+	opposing, crosses := m.sells, func(restPrice Price) bool { return restPrice.Cmp(o.Price) <= 0 }
+	if o.Side == Sell {
+		opposing, crosses = m.buys, func(restPrice Price) bool { return restPrice.Cmp(o.Price) >= 0 }
+	}
+
+	if o.TimeInForce == FOK && !canFill(opposing, crosses, o.Size) {
+		o.Owner.OnUnfilled(m.good, o.Side, o.Size)
+		return r.id
+	}
+
+	m.match(r, o.Side)
+
+	switch o.TimeInForce {
+	case IOC, FOK:
+		if !r.remaining.IsZero() {
+			o.Owner.OnUnfilled(m.good, o.Side, r.remaining)
+		}
+	default: // GTC, GTT
+		if !r.remaining.IsZero() {
+			if o.Side == Buy {
+				m.buys = append(m.buys, r)
+			} else {
+				m.sells = append(m.sells, r)
+			}
+		}
+	}
+
+	return r.id
+}
+
+// PostGroup posts every placement in g as its own order sharing a single
+// GroupID, matching each immediately via Post.
+func (m *continuousMatcher) PostGroup(g *OrderGroup) GroupID {
+	m.nextGroupID++
+	gid := m.nextGroupID
+	for _, pl := range g.Placements {
+		m.Post(&Order{
+			Price:       pl.Price,
+			Size:        pl.Size,
+			Side:        g.Side,
+			Owner:       g.Owner,
+			TimeInForce: g.TimeInForce,
+			ClientID:    g.ClientID,
+			GroupID:     gid,
+		})
+	}
+	return gid
+}
+
+// CancelGroup removes every resting order belonging to gid, firing a single
+// OnUnfilled for their combined remainder - unlike Cancel's per-order report,
+// since a group is meant to be managed and reported on as a unit.
+func (m *continuousMatcher) CancelGroup(gid GroupID) bool {
+	if remaining, owner, found := takeGroup(&m.buys, gid); found {
+		if owner != nil {
+			owner.OnUnfilled(m.good, Buy, remaining)
+		}
+		return true
+	}
+	if remaining, owner, found := takeGroup(&m.sells, gid); found {
+		if owner != nil {
+			owner.OnUnfilled(m.good, Sell, remaining)
+		}
+		return true
+	}
+	return false
+}
+
+// canFill reports whether size can be filled immediately against book,
+// using crosses to decide which resting orders are an acceptable price.
+func canFill(book []*resting, crosses func(Price) bool, size Size) bool {
+	var available Size
+	for _, r := range book {
+		if !crosses(r.order.Price) {
+			continue
+		}
+		available = available.Add(r.remaining)
+		if available.Cmp(size) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// match walks the opposing book in price-time priority, filling r against
+// it until r is filled or no more resting orders cross its price.
+func (m *continuousMatcher) match(r *resting, side Side) {
+	book := &m.sells
+	crosses := func(restPrice Price) bool { return restPrice.Cmp(r.order.Price) <= 0 }
+	if side == Sell {
+		book = &m.buys
+		crosses = func(restPrice Price) bool { return restPrice.Cmp(r.order.Price) >= 0 }
+	}
+
+	sortBook(*book, side.opposite())
+
+	i := 0
+	for i < len(*book) && !r.remaining.IsZero() {
+		opp := (*book)[i]
+		if !crosses(opp.order.Price) {
+			break
+		}
+
+		size := r.remaining
+		if opp.remaining.Cmp(size) < 0 {
+			size = opp.remaining
+		}
+
+		// Trades clear at the resting (maker) order's price.
+		price := opp.order.Price
+
+		// Either side can lack an Owner if it rested across a checkpoint
+		// restore (see restoreOrders) and is only now being crossed.
+		if r.order.Owner != nil {
+			r.order.Owner.OnFill(m.good, side, price, size)
+		}
+		if opp.order.Owner != nil {
+			opp.order.Owner.OnFill(m.good, side.opposite(), price, size)
+		}
+
+		r.remaining = r.remaining.Sub(size)
+		opp.remaining = opp.remaining.Sub(size)
+		if !m.traded {
+			// First trade since Reset: start this period's volume afresh.
+			m.volume = Size{}
+		}
+		m.volume = m.volume.Add(size)
+		if !m.traded || price.Cmp(m.high) > 0 {
+			m.high = price
+		}
+		if !m.traded || price.Cmp(m.low) < 0 {
+			m.low = price
+		}
+		m.traded = true
+
+		if opp.remaining.IsZero() {
+			i++
+		}
+	}
+
+	*book = (*book)[i:]
+}
+
+// opposite returns the other side of a trade.
+func (s Side) opposite() Side {
+	if s == Buy {
+		return Sell
+	}
+	return Buy
+}
+
+// sortBook orders book in price-time priority for the given resting side:
+// buys best-price (highest) first, sells best-price (lowest) first.
+func sortBook(book []*resting, side Side) {
+	if side == Buy {
+		sort.SliceStable(book, func(i, j int) bool { return book[i].order.Price.Cmp(book[j].order.Price) > 0 })
+	} else {
+		sort.SliceStable(book, func(i, j int) bool { return book[i].order.Price.Cmp(book[j].order.Price) < 0 })
+	}
+}
+
+// Cancel removes a resting order from the book, firing OnUnfilled for
+// whatever of it hadn't yet filled. Orders restored from a checkpoint (see
+// restoreOrders) have no Owner to notify, so Cancel just removes them.
+func (m *continuousMatcher) Cancel(id OrderID) bool {
+	if r := takeByID(&m.buys, id); r != nil {
+		if r.order.Owner != nil {
+			r.order.Owner.OnUnfilled(m.good, Buy, r.remaining)
+		}
+		return true
+	}
+	if r := takeByID(&m.sells, id); r != nil {
+		if r.order.Owner != nil {
+			r.order.Owner.OnUnfilled(m.good, Sell, r.remaining)
+		}
+		return true
+	}
+	return false
+}
+
+// Reset marks the end of a trading period: GTT orders that are still
+// resting expire, and the high/low/volume statistics roll over: they keep
+// reporting the period that just ended until the next period's first trade,
+// which starts them afresh. GTC orders are left resting.
+func (m *continuousMatcher) Reset() {
+	m.buys = expireGTT(m.buys, m.good, Buy)
+	m.sells = expireGTT(m.sells, m.good, Sell)
+	m.traded = false
+}
+
+// expireGTT drops GTT orders from book, reporting them via reportUnfilled so
+// a laddered group of GTT orders is notified once as a unit rather than once
+// per expiring placement. See the note on Cancel about restored orders
+// having no Owner to notify.
+func expireGTT(book []*resting, good goods.Good, side Side) []*resting {
+	var expiring []*resting
+	kept := book[:0]
+	for _, r := range book {
+		if r.order.TimeInForce == GTT {
+			expiring = append(expiring, r)
+			continue
+		}
+		kept = append(kept, r)
+	}
+	reportUnfilled(expiring, good, side)
+	return kept
+}
+
+// Bid returns the highest price among resting buy orders.
+func (m *continuousMatcher) Bid() Price {
+	best := Price{}
+	for i, r := range m.buys {
+		if i == 0 || r.order.Price.Cmp(best) > 0 {
+			best = r.order.Price
+		}
+	}
+	return best
+}
+
+// Ask returns the lowest price among resting sell orders.
+func (m *continuousMatcher) Ask() Price {
+	var best Price
+	for i, r := range m.sells {
+		if i == 0 || r.order.Price.Cmp(best) < 0 {
+			best = r.order.Price
+		}
+	}
+	return best
+}
+
+// High returns the highest price traded in the last period.
+func (m *continuousMatcher) High() Price { return m.high }
+
+// Low returns the lowest price traded in the last period.
+func (m *continuousMatcher) Low() Price { return m.low }
+
+// Volume returns the total size traded in the last period.
+func (m *continuousMatcher) Volume() Size { return m.volume }
+
+// Good returns the good this market trades.
+func (m *continuousMatcher) Good() goods.Good { return m.good }
+
+// Book returns up to depth price levels on each side of the resting book,
+// best price first. A depth <= 0 returns every level.
+func (m *continuousMatcher) Book(depth int) (bids, asks []Level) {
+	bids = levels(m.buys, depth, func(a, b Price) bool { return a.Cmp(b) > 0 })
+	asks = levels(m.sells, depth, func(a, b Price) bool { return a.Cmp(b) < 0 })
+	return bids, asks
+}
+
+// MarshalState serializes the order book and last-period statistics.
+func (m *continuousMatcher) MarshalState() ([]byte, error) {
+	s := state{High: m.high, Low: m.low, Volume: m.volume, NextID: m.nextID, NextGroupID: m.nextGroupID}
+	for _, r := range m.buys {
+		s.Buys = append(s.Buys, restingState{r.id, r.order.Price, r.order.Size, r.remaining, r.order.Side, r.order.TimeInForce, r.order.GroupID})
+	}
+	for _, r := range m.sells {
+		s.Sells = append(s.Sells, restingState{r.id, r.order.Price, r.order.Size, r.remaining, r.order.Side, r.order.TimeInForce, r.order.GroupID})
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalState restores state previously produced by MarshalState. Like
+// doubleAuction, restored resting orders come back with a nil Owner.
+func (m *continuousMatcher) UnmarshalState(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	m.high, m.low, m.volume = s.High, s.Low, s.Volume
+	m.nextID = s.NextID
+	m.nextGroupID = s.NextGroupID
+	m.buys = restoreOrders(s.Buys)
+	m.sells = restoreOrders(s.Sells)
+	return nil
+}
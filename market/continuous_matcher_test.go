@@ -0,0 +1,172 @@
+package market
+
+import (
+	"testing"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+func TestContinuousMatcherFillsImmediately(t *testing.T) {
+	// Situation: a resting sell is already in the book, so a crossing buy
+	// should fill right away instead of waiting for Reset.
+	s := &fakeAgent{}
+	b := &fakeAgent{}
+
+	m := NewContinuousMatcher(goods.Labour)
+
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(100), Side: Sell, Owner: s})
+	m.Post(&Order{Price: NewPrice(11), Size: NewSize(40), Side: Buy, Owner: b})
+
+	want := &fakeAgent{NewPrice(10), NewSize(40), Buy, Size{}, 0}
+	if *b != *want {
+		t.Errorf("buyer: got %v, want %v", b, want)
+	}
+	if s.fillPrice != NewPrice(10) || s.fillSize != NewSize(40) || s.fillSide != Sell {
+		t.Errorf("seller: got price %v size %v side %v, want 10/40/sell", s.fillPrice, s.fillSize, s.fillSide)
+	}
+}
+
+func TestContinuousMatcherIOCDiscardsRemainder(t *testing.T) {
+	// An IOC buy that only partially crosses should fill what it can and
+	// report the rest unfilled instead of resting in the book.
+	s := &fakeAgent{}
+	b := &fakeAgent{}
+
+	m := NewContinuousMatcher(goods.Labour)
+
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(30), Side: Sell, Owner: s})
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(100), Side: Buy, Owner: b, TimeInForce: IOC})
+
+	if b.fillSize != NewSize(30) || b.fillSide != Buy {
+		t.Errorf("buyer fill: got size %v side %v, want 30 buy", b.fillSize, b.fillSide)
+	}
+	if b.unfilledSize != NewSize(70) || b.unfilledSide != Buy {
+		t.Errorf("buyer unfilled: got size %v side %v, want 70 buy", b.unfilledSize, b.unfilledSide)
+	}
+
+	bids, _ := m.Book(int(DepthFull))
+	if len(bids) != 0 {
+		t.Errorf("IOC remainder should not rest, got bids %v", bids)
+	}
+}
+
+func TestContinuousMatcherFOKRejectsWhenUnfillable(t *testing.T) {
+	// A FOK order that can't be filled in full should be rejected outright,
+	// leaving the resting book untouched.
+	s := &fakeAgent{}
+	b := &fakeAgent{}
+
+	m := NewContinuousMatcher(goods.Labour)
+
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(30), Side: Sell, Owner: s})
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(100), Side: Buy, Owner: b, TimeInForce: FOK})
+
+	if b.fillSize != (Size{}) {
+		t.Errorf("FOK buyer should not have been filled, got fill size %v", b.fillSize)
+	}
+	if b.unfilledSize != NewSize(100) || b.unfilledSide != Buy {
+		t.Errorf("FOK buyer unfilled: got size %v side %v, want 100 buy", b.unfilledSize, b.unfilledSide)
+	}
+
+	_, asks := m.Book(int(DepthFull))
+	if len(asks) != 1 || asks[0].Size != NewSize(30) {
+		t.Errorf("FOK rejection should leave the resting sell untouched, got asks %v", asks)
+	}
+}
+
+func TestContinuousMatcherFOKFillsWhenPossible(t *testing.T) {
+	s := &fakeAgent{}
+	b := &fakeAgent{}
+
+	m := NewContinuousMatcher(goods.Labour)
+
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(100), Side: Sell, Owner: s})
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(40), Side: Buy, Owner: b, TimeInForce: FOK})
+
+	if b.fillSize != NewSize(40) || b.fillSide != Buy {
+		t.Errorf("FOK buyer: got size %v side %v, want 40 buy", b.fillSize, b.fillSide)
+	}
+	if b.unfilledSize != (Size{}) {
+		t.Errorf("FOK buyer should have no unfilled remainder, got %v", b.unfilledSize)
+	}
+}
+
+func TestContinuousMatcherGTTExpiresAcrossPeriods(t *testing.T) {
+	// A GTT order that doesn't fill should survive until the period ends,
+	// then expire on the next Reset. A GTC order alongside it should
+	// keep resting.
+	gtt := &fakeAgent{}
+	gtc := &fakeAgent{}
+
+	m := NewContinuousMatcher(goods.Labour)
+
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(50), Side: Buy, Owner: gtt, TimeInForce: GTT})
+	m.Post(&Order{Price: NewPrice(9), Size: NewSize(50), Side: Buy, Owner: gtc})
+
+	// Still resting mid-period.
+	bids, _ := m.Book(int(DepthFull))
+	if len(bids) != 2 {
+		t.Fatalf("expected both orders resting before Reset, got %v", bids)
+	}
+
+	m.Reset()
+
+	if gtt.unfilledSize != NewSize(50) || gtt.unfilledSide != Buy {
+		t.Errorf("GTT order should have expired, got unfilled size %v side %v", gtt.unfilledSize, gtt.unfilledSide)
+	}
+
+	bids, _ = m.Book(int(DepthFull))
+	if len(bids) != 1 || bids[0].Price != NewPrice(9) {
+		t.Errorf("GTC order should still be resting after Reset, got bids %v", bids)
+	}
+}
+
+func TestContinuousMatcherRestoreThenMatch(t *testing.T) {
+	// A GTC order that's still resting when a checkpoint is taken comes back
+	// from UnmarshalState with no Owner (see restingState). Crossing it
+	// later - on either side of the trade - must not panic trying to notify
+	// a nil Owner.
+	b := &fakeAgent{}
+
+	m := NewContinuousMatcher(goods.Labour)
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(50), Side: Buy, Owner: b})
+
+	data, err := m.MarshalState()
+	if err != nil {
+		t.Fatalf("MarshalState: %v", err)
+	}
+
+	restored := NewContinuousMatcher(goods.Labour)
+	if err := restored.UnmarshalState(data); err != nil {
+		t.Fatalf("UnmarshalState: %v", err)
+	}
+
+	s := &fakeAgent{}
+	restored.Post(&Order{Price: NewPrice(10), Size: NewSize(30), Side: Sell, Owner: s})
+
+	if s.fillSize != NewSize(30) || s.fillSide != Sell {
+		t.Errorf("seller: got fill size %v side %v, want 30 sell", s.fillSize, s.fillSide)
+	}
+
+	bids, _ := restored.Book(int(DepthFull))
+	if len(bids) != 1 || bids[0].Size != NewSize(20) {
+		t.Errorf("restored buy should have the matched size removed, got bids %v", bids)
+	}
+}
+
+func TestContinuousMatcherCancel(t *testing.T) {
+	b := &fakeAgent{}
+
+	m := NewContinuousMatcher(goods.Labour)
+
+	id := m.Post(&Order{Price: NewPrice(10), Size: NewSize(50), Side: Buy, Owner: b})
+	if !m.Cancel(id) {
+		t.Fatal("expected Cancel to find the resting order")
+	}
+	if b.unfilledSize != NewSize(50) || b.unfilledSide != Buy {
+		t.Errorf("cancelled order: got unfilled size %v side %v, want 50 buy", b.unfilledSize, b.unfilledSide)
+	}
+	if m.Cancel(id) {
+		t.Error("expected Cancel to fail the second time")
+	}
+}
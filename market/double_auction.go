@@ -0,0 +1,393 @@
+package market
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/robbrit/econerra/goods"
+)
+
+// resting is an order sitting in the book, along with how much of it is
+// still unfilled.
+type resting struct {
+	id        OrderID
+	order     *Order
+	remaining Size
+}
+
+// doubleAuction is a batch double auction: orders accumulate until Reset is
+// called, at which point crossing orders are matched in price-time
+// priority and the book is cleared for the next period.
+type doubleAuction struct {
+	good goods.Good
+	info goods.Info
+
+	nextID      OrderID
+	nextGroupID GroupID
+	buys        []*resting
+	sells       []*resting
+
+	high, low Price
+	volume    Size
+}
+
+// NewDoubleAuction creates a Market that clears via batch double auction:
+// orders accumulate between calls to Reset, which matches the accumulated
+// book and notifies agents of fills and unfilled remainders.
+func NewDoubleAuction(good goods.Good) Market {
+	return &doubleAuction{
+		good: good,
+		info: good.Info(),
+	}
+}
+
+// Post adds an order to the book, truncating its price and size to the
+// good's tick size and lot size. Batch double auctions settle everything
+// at Reset regardless of TimeInForce, so every order behaves as GTC until
+// then.
+func (m *doubleAuction) Post(o *Order) OrderID {
+	o.Price = o.Price.TruncatePrice(Price{m.info.TickSize})
+	o.Size = o.Size.TruncateQuantity(Size{m.info.LotSize})
+
+	m.nextID++
+	r := &resting{id: m.nextID, order: o, remaining: o.Size}
+	if o.Side == Buy {
+		m.buys = append(m.buys, r)
+	} else {
+		m.sells = append(m.sells, r)
+	}
+	return r.id
+}
+
+// Cancel removes a resting order from the book before it can be matched by
+// Reset, reporting whether an order with that ID was found.
+func (m *doubleAuction) Cancel(id OrderID) bool {
+	return takeByID(&m.buys, id) != nil || takeByID(&m.sells, id) != nil
+}
+
+// PostGroup posts every placement in g as its own order sharing a single
+// GroupID, truncating each to the good's tick/lot size via Post.
+func (m *doubleAuction) PostGroup(g *OrderGroup) GroupID {
+	m.nextGroupID++
+	gid := m.nextGroupID
+	for _, pl := range g.Placements {
+		m.Post(&Order{
+			Price:       pl.Price,
+			Size:        pl.Size,
+			Side:        g.Side,
+			Owner:       g.Owner,
+			TimeInForce: g.TimeInForce,
+			ClientID:    g.ClientID,
+			GroupID:     gid,
+		})
+	}
+	return gid
+}
+
+// CancelGroup removes every resting order belonging to gid, the same way
+// Cancel removes a single order: silently, since anything still in the
+// book hasn't been matched by Reset yet.
+func (m *doubleAuction) CancelGroup(gid GroupID) bool {
+	_, _, foundBuys := takeGroup(&m.buys, gid)
+	_, _, foundSells := takeGroup(&m.sells, gid)
+	return foundBuys || foundSells
+}
+
+// takeByID removes and returns the resting order with the given ID from
+// book, or nil if none was found.
+func takeByID(book *[]*resting, id OrderID) *resting {
+	for i, r := range *book {
+		if r.id == id {
+			*book = append((*book)[:i], (*book)[i+1:]...)
+			return r
+		}
+	}
+	return nil
+}
+
+// takeGroup removes every resting order in book belonging to gid, reporting
+// their combined remaining size, their shared owner (every placement in an
+// OrderGroup has the same one), and whether any were found.
+func takeGroup(book *[]*resting, gid GroupID) (remaining Size, owner MarketAgent, found bool) {
+	kept := (*book)[:0]
+	for _, r := range *book {
+		if r.order.GroupID == gid {
+			remaining = remaining.Add(r.remaining)
+			owner = r.order.Owner
+			found = true
+			continue
+		}
+		kept = append(kept, r)
+	}
+	*book = kept
+	return remaining, owner, found
+}
+
+// groupRemainder accumulates the unfilled size across every placement of an
+// order group, so the group can be reported with one OnUnfilled call
+// instead of one per placement.
+type groupRemainder struct {
+	owner     MarketAgent
+	remaining Size
+}
+
+// reportUnfilled fires OnUnfilled for every resting order in book with a
+// nonzero remainder. Orders outside a group report individually; orders
+// sharing a GroupID are summed and reported once, so an agent managing a
+// laddered OrderGroup sees a single combined remainder instead of one event
+// per price level.
+//
+// book is copied up front because an OnUnfilled callback is free to Cancel
+// another resting order in the same market - which compacts the live
+// m.buys/m.sells slice in place - and that must not corrupt this loop's
+// view of who's left to notify.
+func reportUnfilled(book []*resting, good goods.Good, side Side) {
+	book = append([]*resting(nil), book...)
+
+	groups := map[GroupID]*groupRemainder{}
+	var order []GroupID
+
+	for _, r := range book {
+		if r.remaining.IsZero() {
+			continue
+		}
+		if r.order.GroupID == 0 {
+			if r.order.Owner != nil {
+				r.order.Owner.OnUnfilled(good, side, r.remaining)
+			}
+			continue
+		}
+		gr, ok := groups[r.order.GroupID]
+		if !ok {
+			gr = &groupRemainder{owner: r.order.Owner}
+			groups[r.order.GroupID] = gr
+			order = append(order, r.order.GroupID)
+		}
+		gr.remaining = gr.remaining.Add(r.remaining)
+	}
+
+	for _, gid := range order {
+		gr := groups[gid]
+		if gr.owner != nil {
+			gr.owner.OnUnfilled(good, side, gr.remaining)
+		}
+	}
+}
+
+// Reset matches the accumulated book, firing OnFill for every match and
+// OnUnfilled for any size left over, then clears the book for the next
+// period.
+func (m *doubleAuction) Reset() {
+	// Buys are matched highest price (and earliest) first, sells lowest
+	// price (and earliest) first - standard price-time priority.
+	sort.SliceStable(m.buys, func(i, j int) bool {
+		return m.buys[i].order.Price.Cmp(m.buys[j].order.Price) > 0
+	})
+	sort.SliceStable(m.sells, func(i, j int) bool {
+		return m.sells[i].order.Price.Cmp(m.sells[j].order.Price) < 0
+	})
+
+	m.volume = Size{}
+	var high, low Price
+	var traded bool
+
+	i, j := 0, 0
+	for i < len(m.buys) && j < len(m.sells) {
+		buy := m.buys[i]
+		sell := m.sells[j]
+
+		if buy.order.Price.Cmp(sell.order.Price) < 0 {
+			// Best remaining buy is below the best remaining ask - nothing
+			// left to match.
+			break
+		}
+
+		size := buy.remaining
+		if sell.remaining.Cmp(size) < 0 {
+			size = sell.remaining
+		}
+
+		// Trades clear at the resting (maker) order's price: the ask.
+		price := sell.order.Price
+
+		// Either side can lack an Owner if it rested across a checkpoint
+		// restore (see restoreOrders) and is only now being matched.
+		if buy.order.Owner != nil {
+			buy.order.Owner.OnFill(m.good, Buy, price, size)
+		}
+		if sell.order.Owner != nil {
+			sell.order.Owner.OnFill(m.good, Sell, price, size)
+		}
+
+		buy.remaining = buy.remaining.Sub(size)
+		sell.remaining = sell.remaining.Sub(size)
+		m.volume = m.volume.Add(size)
+
+		if !traded || price.Cmp(high) > 0 {
+			high = price
+		}
+		if !traded || price.Cmp(low) < 0 {
+			low = price
+		}
+		traded = true
+
+		if buy.remaining.IsZero() {
+			i++
+		}
+		if sell.remaining.IsZero() {
+			j++
+		}
+	}
+
+	reportUnfilled(m.buys, m.good, Buy)
+	reportUnfilled(m.sells, m.good, Sell)
+
+	if traded {
+		m.high, m.low = high, low
+	}
+	m.buys = nil
+	m.sells = nil
+}
+
+// Bid returns the highest price among resting buy orders.
+func (m *doubleAuction) Bid() Price {
+	best := Price{}
+	for i, r := range m.buys {
+		if i == 0 || r.order.Price.Cmp(best) > 0 {
+			best = r.order.Price
+		}
+	}
+	return best
+}
+
+// Ask returns the lowest price among resting sell orders.
+func (m *doubleAuction) Ask() Price {
+	var best Price
+	for i, r := range m.sells {
+		if i == 0 || r.order.Price.Cmp(best) < 0 {
+			best = r.order.Price
+		}
+	}
+	return best
+}
+
+// High returns the highest price traded in the last period.
+func (m *doubleAuction) High() Price { return m.high }
+
+// Low returns the lowest price traded in the last period.
+func (m *doubleAuction) Low() Price { return m.low }
+
+// Volume returns the total size traded in the last period.
+func (m *doubleAuction) Volume() Size { return m.volume }
+
+// Good returns the good this market trades.
+func (m *doubleAuction) Good() goods.Good { return m.good }
+
+// Book returns up to depth price levels on each side of the resting book,
+// best price first. A depth <= 0 returns every level.
+func (m *doubleAuction) Book(depth int) (bids, asks []Level) {
+	bids = levels(m.buys, depth, func(a, b Price) bool { return a.Cmp(b) > 0 })
+	asks = levels(m.sells, depth, func(a, b Price) bool { return a.Cmp(b) < 0 })
+	return bids, asks
+}
+
+// levels aggregates resting orders by price into sorted levels, ordered by
+// betterThan (buys sort highest-first, sells sort lowest-first), truncated
+// to depth price levels. A depth <= 0 returns every level.
+func levels(orders []*resting, depth int, betterThan func(a, b Price) bool) []Level {
+	byPrice := map[Price]*Level{}
+	var prices []Price
+
+	for _, r := range orders {
+		if r.remaining.IsZero() {
+			continue
+		}
+		l, ok := byPrice[r.order.Price]
+		if !ok {
+			l = &Level{Price: r.order.Price}
+			byPrice[r.order.Price] = l
+			prices = append(prices, r.order.Price)
+		}
+		l.Size = l.Size.Add(r.remaining)
+		l.NumOrders++
+	}
+
+	sort.Slice(prices, func(i, j int) bool { return betterThan(prices[i], prices[j]) })
+
+	if depth > 0 && depth < len(prices) {
+		prices = prices[:depth]
+	}
+
+	out := make([]Level, len(prices))
+	for i, price := range prices {
+		out[i] = *byPrice[price]
+	}
+	return out
+}
+
+// restingState is the serializable form of a resting order. It deliberately
+// omits Order.Owner: a restored market has no way to reconnect an order to
+// the live agent that posted it, so restoring a checkpoint only makes sense
+// as part of restoring the whole Simulation, which re-posts each agent's
+// orders for the period rather than relying on the book snapshot alone.
+type restingState struct {
+	ID          OrderID
+	Price       Price
+	Size        Size
+	Remaining   Size
+	Side        Side
+	TimeInForce TimeInForce
+	GroupID     GroupID
+}
+
+// state is the serializable snapshot of a doubleAuction.
+type state struct {
+	Buys, Sells []restingState
+	High, Low   Price
+	Volume      Size
+	NextID      OrderID
+	NextGroupID GroupID
+}
+
+// MarshalState serializes the order book and last-period statistics.
+func (m *doubleAuction) MarshalState() ([]byte, error) {
+	s := state{High: m.high, Low: m.low, Volume: m.volume, NextID: m.nextID, NextGroupID: m.nextGroupID}
+	for _, r := range m.buys {
+		s.Buys = append(s.Buys, restingState{r.id, r.order.Price, r.order.Size, r.remaining, r.order.Side, r.order.TimeInForce, r.order.GroupID})
+	}
+	for _, r := range m.sells {
+		s.Sells = append(s.Sells, restingState{r.id, r.order.Price, r.order.Size, r.remaining, r.order.Side, r.order.TimeInForce, r.order.GroupID})
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalState restores state previously produced by MarshalState. Resting
+// orders come back with a nil Owner - see restingState.
+func (m *doubleAuction) UnmarshalState(data []byte) error {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	m.high, m.low, m.volume = s.High, s.Low, s.Volume
+	m.nextID = s.NextID
+	m.nextGroupID = s.NextGroupID
+	m.buys = restoreOrders(s.Buys)
+	m.sells = restoreOrders(s.Sells)
+	return nil
+}
+
+func restoreOrders(states []restingState) []*resting {
+	if states == nil {
+		return nil
+	}
+	out := make([]*resting, len(states))
+	for i, rs := range states {
+		out[i] = &resting{
+			id:        rs.ID,
+			order:     &Order{Price: rs.Price, Size: rs.Size, Side: rs.Side, TimeInForce: rs.TimeInForce, GroupID: rs.GroupID},
+			remaining: rs.Remaining,
+		}
+	}
+	return out
+}
@@ -36,10 +36,10 @@ func TestMarket(t *testing.T) {
 
 	m := NewDoubleAuction(goods.Labour)
 
-	m.Post(&Order{10, 100, Sell, s})
-	m.Post(&Order{12, 10, Buy, b1})
-	m.Post(&Order{10, 200, Buy, b2})
-	m.Post(&Order{8, 1000, Buy, b3})
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(100), Side: Sell, Owner: s})
+	m.Post(&Order{Price: NewPrice(12), Size: NewSize(10), Side: Buy, Owner: b1})
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(200), Side: Buy, Owner: b2})
+	m.Post(&Order{Price: NewPrice(8), Size: NewSize(1000), Side: Buy, Owner: b3})
 
 	m.Reset()
 
@@ -51,22 +51,22 @@ func TestMarket(t *testing.T) {
 		{
 			"high buy should get filled at 10",
 			b1,
-			&fakeAgent{10, 10, Buy, 0, 0},
+			&fakeAgent{NewPrice(10), NewSize(10), Buy, Size{}, 0},
 		},
 		{
 			"mid buy should get partially filled",
 			b2,
-			&fakeAgent{10, 90, Buy, 110, Buy},
+			&fakeAgent{NewPrice(10), NewSize(90), Buy, NewSize(110), Buy},
 		},
 		{
 			"low buy should not get filled at all",
 			b3,
-			&fakeAgent{0, 0, 0, 1000, Buy},
+			&fakeAgent{Price{}, Size{}, 0, NewSize(1000), Buy},
 		},
 		{
 			"sell should have latest fill values",
 			s,
-			&fakeAgent{10, 90, Sell, 0, 0},
+			&fakeAgent{NewPrice(10), NewSize(90), Sell, Size{}, 0},
 		},
 	} {
 		if *test.agent != *test.wantAgent {
@@ -74,3 +74,110 @@ func TestMarket(t *testing.T) {
 		}
 	}
 }
+
+func TestDoubleAuctionRestoreThenMatch(t *testing.T) {
+	// A GTC order that's still resting when a checkpoint is taken comes back
+	// from UnmarshalState with no Owner (see restingState). Matching it on
+	// the next Reset must not panic trying to notify a nil Owner.
+	b := &fakeAgent{}
+
+	m := NewDoubleAuction(goods.Labour)
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(50), Side: Buy, Owner: b})
+
+	data, err := m.MarshalState()
+	if err != nil {
+		t.Fatalf("MarshalState: %v", err)
+	}
+
+	restored := NewDoubleAuction(goods.Labour)
+	if err := restored.UnmarshalState(data); err != nil {
+		t.Fatalf("UnmarshalState: %v", err)
+	}
+
+	s := &fakeAgent{}
+	restored.Post(&Order{Price: NewPrice(10), Size: NewSize(30), Side: Sell, Owner: s})
+	restored.Reset()
+
+	if s.fillSize != NewSize(30) || s.fillSide != Sell {
+		t.Errorf("seller: got fill size %v side %v, want 30 sell", s.fillSize, s.fillSide)
+	}
+}
+
+// cancellingAgent cancels another order as soon as its own goes unfilled -
+// the same thing Arbitrageur.OnUnfilled does to the rest of a cycle's legs -
+// so reportUnfilled can be tested against a Cancel landing mid-report.
+type cancellingAgent struct {
+	fakeAgent
+	market   Market
+	toCancel OrderID
+}
+
+func (c *cancellingAgent) OnUnfilled(g goods.Good, s Side, q Size) {
+	c.fakeAgent.OnUnfilled(g, s, q)
+	c.market.Cancel(c.toCancel)
+}
+
+func TestDoubleAuctionReportUnfilledSurvivesCancelMidReport(t *testing.T) {
+	// Situation: four resting buys, none crossed by any sell, so Reset
+	// reports every one of them unfilled. The second one's OnUnfilled
+	// cancels the third - exactly what Arbitrageur does to a sibling leg -
+	// which must not corrupt reportUnfilled's notifications for the orders
+	// that come after it in the book.
+	m := NewDoubleAuction(goods.Labour)
+
+	before := &fakeAgent{}
+	m.Post(&Order{Price: NewPrice(9), Size: NewSize(10), Side: Buy, Owner: before})
+
+	arb := &cancellingAgent{market: m}
+	m.Post(&Order{Price: NewPrice(9), Size: NewSize(20), Side: Buy, Owner: arb})
+
+	mid := &fakeAgent{}
+	midID := m.Post(&Order{Price: NewPrice(9), Size: NewSize(30), Side: Buy, Owner: mid})
+	arb.toCancel = midID
+
+	after := &fakeAgent{}
+	m.Post(&Order{Price: NewPrice(9), Size: NewSize(40), Side: Buy, Owner: after})
+
+	m.Reset()
+
+	if before.unfilledSize != NewSize(10) {
+		t.Errorf("before: got unfilled size %v, want 10", before.unfilledSize)
+	}
+	if arb.unfilledSize != NewSize(20) {
+		t.Errorf("arb: got unfilled size %v, want 20", arb.unfilledSize)
+	}
+	if after.unfilledSize != NewSize(40) {
+		t.Errorf("after: got unfilled size %v, want 40 - a corrupted report would double-fire or skip this one", after.unfilledSize)
+	}
+}
+
+func TestMarketBook(t *testing.T) {
+	// Situation: two price levels on each side, before any matching happens.
+	s1, s2 := &fakeAgent{}, &fakeAgent{}
+	b1, b2, b3 := &fakeAgent{}, &fakeAgent{}, &fakeAgent{}
+
+	m := NewDoubleAuction(goods.Labour)
+
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(100), Side: Sell, Owner: s1})
+	m.Post(&Order{Price: NewPrice(10), Size: NewSize(50), Side: Sell, Owner: s2})
+	m.Post(&Order{Price: NewPrice(11), Size: NewSize(20), Side: Sell, Owner: s2})
+	m.Post(&Order{Price: NewPrice(9), Size: NewSize(30), Side: Buy, Owner: b1})
+	m.Post(&Order{Price: NewPrice(8), Size: NewSize(40), Side: Buy, Owner: b2})
+	m.Post(&Order{Price: NewPrice(7), Size: NewSize(10), Side: Buy, Owner: b3})
+
+	bids, asks := m.Book(int(DepthTop))
+	if len(bids) != 1 || bids[0] != (Level{NewPrice(9), NewSize(30), 1}) {
+		t.Errorf("DepthTop bids: got %v, want a single level at 9/30", bids)
+	}
+	if len(asks) != 1 || asks[0] != (Level{NewPrice(10), NewSize(150), 2}) {
+		t.Errorf("DepthTop asks: got %v, want a single aggregated level at 10/150", asks)
+	}
+
+	bids, asks = m.Book(int(DepthFull))
+	if len(bids) != 3 {
+		t.Errorf("DepthFull bids: got %d levels, want 3", len(bids))
+	}
+	if len(asks) != 2 {
+		t.Errorf("DepthFull asks: got %d levels, want 2", len(asks))
+	}
+}
@@ -1,17 +1,74 @@
 package market
 
-import "github.com/robbrit/econerra/goods"
+import (
+	"github.com/robbrit/econerra/fixedpoint"
+	"github.com/robbrit/econerra/goods"
+)
 
 // A Side represents the side that an order is on (buy vs. sell)
+//
 //go:generate stringer -type=Side
 type Side uint8
 
-// A Price is how much it costs to buy a good.
-type Price uint32
+// A Price is how much it costs to buy a good, represented as a fixed-point
+// decimal so that markets can clear at fractional ticks instead of rounding
+// to the nearest whole unit.
+type Price struct {
+	fixedpoint.Value
+}
+
+// NewPrice creates a Price from an integer number of units.
+func NewPrice(i int64) Price { return Price{fixedpoint.FromInt(i)} }
+
+// Add returns p + other.
+func (p Price) Add(other Price) Price { return Price{p.Value.Add(other.Value)} }
+
+// Sub returns p - other.
+func (p Price) Sub(other Price) Price { return Price{p.Value.Sub(other.Value)} }
+
+// Mul returns p * other.
+func (p Price) Mul(other Price) Price { return Price{p.Value.Mul(other.Value)} }
+
+// Div returns p / other.
+func (p Price) Div(other Price) Price { return Price{p.Value.Div(other.Value)} }
+
+// Cmp compares p to other, returning -1, 0, or 1 if p is less than, equal
+// to, or greater than other.
+func (p Price) Cmp(other Price) int { return p.Value.Cmp(other.Value) }
+
+// TruncatePrice rounds p down to the nearest multiple of tickSize, which is
+// typically a good's Info.TickSize.
+func (p Price) TruncatePrice(tickSize Price) Price { return Price{p.Value.Truncate(tickSize.Value)} }
+
+// A Size is a quantity of a good, represented as a fixed-point decimal so
+// that firms with continuous production functions can trade fractional
+// amounts instead of only whole units.
+type Size struct {
+	fixedpoint.Value
+}
+
+// NewSize creates a Size from an integer number of units.
+func NewSize(i int64) Size { return Size{fixedpoint.FromInt(i)} }
+
+// Add returns s + other.
+func (s Size) Add(other Size) Size { return Size{s.Value.Add(other.Value)} }
+
+// Sub returns s - other.
+func (s Size) Sub(other Size) Size { return Size{s.Value.Sub(other.Value)} }
+
+// Mul returns s * other.
+func (s Size) Mul(other Size) Size { return Size{s.Value.Mul(other.Value)} }
 
-// A Size is a quantity of a good.
-// TODO(rob): What if it's possible to buy/sell fractional amounts of a good?
-type Size uint32
+// Div returns s / other.
+func (s Size) Div(other Size) Size { return Size{s.Value.Div(other.Value)} }
+
+// Cmp compares s to other, returning -1, 0, or 1 if s is less than, equal
+// to, or greater than other.
+func (s Size) Cmp(other Size) int { return s.Value.Cmp(other.Value) }
+
+// TruncateQuantity rounds s down to the nearest multiple of minStep, which
+// is typically a good's Info.LotSize.
+func (s Size) TruncateQuantity(minStep Size) Size { return Size{s.Value.Truncate(minStep.Value)} }
 
 const (
 	// Buy is an order to buy things.
@@ -20,10 +77,52 @@ const (
 	Sell
 )
 
+// An OrderID identifies a specific order a Market accepted via Post, so its
+// owner can later Cancel it.
+type OrderID uint64
+
+// A GroupID identifies a set of orders posted together via PostGroup, so
+// they can be cancelled as a unit via CancelGroup. A group's unfilled
+// remainder is also reported as a single OnUnfilled call summed across
+// every placement, rather than one call per placement. The exception is a
+// continuous matcher's IOC and FOK groups: each of their placements reports
+// its own unfilled remainder as soon as it's posted.
+type GroupID uint64
+
+// A TimeInForce controls how long an order may rest in the book before it
+// is no longer eligible to match.
+//
+//go:generate stringer -type=TimeInForce
+type TimeInForce uint8
+
+const (
+	// GTC (good-till-cancelled) rests in the book until filled or
+	// explicitly cancelled.
+	GTC TimeInForce = iota
+	// IOC (immediate-or-cancel) fills what it can immediately and
+	// discards the rest instead of resting.
+	IOC
+	// FOK (fill-or-kill) fills entirely and immediately, or not at all.
+	FOK
+	// GTT (good-till-time) rests in the book like GTC, but expires at the
+	// end of the current trading period if still unfilled.
+	GTT
+)
+
 // Market represents a market for buying and selling goods.
 type Market interface {
-	// Post an order to this market.
-	Post(*Order)
+	// Post an order to this market, returning an OrderID its owner can
+	// use to Cancel it later.
+	Post(*Order) OrderID
+	// Cancel a resting order, reporting whether it was found and removed.
+	Cancel(OrderID) bool
+	// PostGroup posts every placement in g as its own order, all sharing
+	// a single GroupID that's returned so the group can later be
+	// cancelled as a unit via CancelGroup.
+	PostGroup(*OrderGroup) GroupID
+	// CancelGroup cancels every still-resting order in the group,
+	// reporting whether any were found.
+	CancelGroup(GroupID) bool
 	// Reset the market.
 	Reset()
 	// Get the highest price for unfilled buy orders.
@@ -38,6 +137,39 @@ type Market interface {
 	Volume() Size
 	// Gets the good bought/sold in this market.
 	Good() goods.Good
+	// Book returns up to depth price levels on each side of the book, best
+	// price first. A depth of DepthFull (or any value <= 0) returns every
+	// level.
+	Book(depth int) (bids, asks []Level)
+	// MarshalState serializes the market's order book and last-period
+	// statistics so a simulation can be checkpointed and later restored.
+	MarshalState() ([]byte, error)
+	// UnmarshalState restores state previously produced by MarshalState.
+	UnmarshalState([]byte) error
+}
+
+// A BookDepth is a commonly requested number of price levels to fetch from
+// a Market's order book.
+type BookDepth int
+
+const (
+	// DepthFull requests every price level in the book.
+	DepthFull BookDepth = 0
+	// DepthTop requests only the best price level on each side.
+	DepthTop BookDepth = 1
+	// DepthMedium requests a medium amount of book depth.
+	DepthMedium BookDepth = 20
+)
+
+// A Level is the resting orders at a single price, aggregated for display or
+// decision-making purposes.
+type Level struct {
+	// Price is the price of this level.
+	Price Price
+	// Size is the total resting size at this price.
+	Size Size
+	// NumOrders is how many individual orders make up this level.
+	NumOrders int
 }
 
 // A MarketAgent is an agent that trades in the market, and can be notified of
@@ -55,4 +187,40 @@ type Order struct {
 	Size  Size
 	Side  Side
 	Owner MarketAgent
+
+	// TimeInForce controls how long this order may rest before it stops
+	// being eligible to match. The zero value is GTC.
+	TimeInForce TimeInForce
+	// ClientID is an opaque label the owner can use to correlate this
+	// order with its own bookkeeping; the market never interprets it.
+	ClientID string
+
+	// GroupID ties this order to others posted together via PostGroup.
+	// The zero value means this order isn't part of a group, and it
+	// reports its own unfilled remainder individually as usual.
+	GroupID GroupID
+}
+
+// A Placement is one price/size level of an OrderGroup.
+type Placement struct {
+	Price Price
+	Size  Size
+}
+
+// An OrderGroup is a set of placements - e.g. a laddered sequence of sell
+// orders at descending prices - posted together as a unit via PostGroup.
+// Every placement shares the group's Side, Owner, TimeInForce, and
+// ClientID.
+type OrderGroup struct {
+	Placements []Placement
+	Side       Side
+	Owner      MarketAgent
+
+	// TimeInForce controls how long every placement in the group may
+	// rest before it stops being eligible to match. The zero value is
+	// GTC.
+	TimeInForce TimeInForce
+	// ClientID is an opaque label the owner can use to correlate this
+	// group with its own bookkeeping; the market never interprets it.
+	ClientID string
 }
@@ -0,0 +1,34 @@
+//go:build redis
+
+package persistence
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore is a Store backed by Redis, useful for sharing checkpoints
+// across worker processes running a parameter sweep.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a RedisStore using an already-configured client.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Save writes data under key.
+func (s *RedisStore) Save(key string, data []byte) error {
+	return s.client.Set(context.Background(), key, data, 0).Err()
+}
+
+// Load reads the data stored under key.
+func (s *RedisStore) Load(key string) ([]byte, error) {
+	data, err := s.client.Get(context.Background(), key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
@@ -0,0 +1,52 @@
+//go:build sqlite
+
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore is a Store backed by a single SQLite table, useful for
+// checkpointing a long local run without standing up a separate service.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (and, if needed, initializes) a SQLite-backed Store
+// at path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS checkpoints (key TEXT PRIMARY KEY, data BLOB)`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Save writes data under key.
+func (s *SQLiteStore) Save(key string, data []byte) error {
+	_, err := s.db.Exec(`INSERT INTO checkpoints (key, data) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET data = excluded.data`, key, data)
+	return err
+}
+
+// Load reads the data stored under key.
+func (s *SQLiteStore) Load(key string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM checkpoints WHERE key = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Close releases the underlying database handle.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
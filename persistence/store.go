@@ -0,0 +1,19 @@
+// Package persistence provides a key/value Store abstraction used to
+// snapshot and restore simulation state, plus concrete backends behind
+// build tags so that callers only pull in the client library they need.
+package persistence
+
+import "errors"
+
+// ErrNotFound is returned by Load when no value exists for a key.
+var ErrNotFound = errors.New("persistence: key not found")
+
+// A Store saves and loads opaque byte blobs by key. Implementations should
+// be safe for concurrent use.
+type Store interface {
+	// Save writes data under key, overwriting any existing value.
+	Save(key string, data []byte) error
+	// Load reads the data stored under key, returning ErrNotFound if it
+	// doesn't exist.
+	Load(key string) ([]byte, error)
+}
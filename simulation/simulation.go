@@ -0,0 +1,121 @@
+// Package simulation drives a set of agents through repeated trading
+// periods against a shared set of markets, with support for checkpointing
+// and restoring the run via the persistence package.
+package simulation
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/persistence"
+)
+
+// A Simulation ticks a set of firms through trading periods against the
+// markets described by Params.
+type Simulation struct {
+	// Params is shared with every firm's Act call.
+	Params *agents.Parameters
+	// Firms are the agents acting each tick.
+	Firms []*agents.Firm
+}
+
+// New creates a Simulation over the given firms, trading under params.
+func New(params *agents.Parameters, firms []*agents.Firm) *Simulation {
+	return &Simulation{Params: params, Firms: firms}
+}
+
+// Tick runs one trading period: every firm acts, then every market resets,
+// matching orders and notifying agents of fills. Markets reset in a fixed
+// order (sorted by their checkpoint key) rather than Go's randomized map
+// iteration order, so that a given Params produces the same sequence of
+// notifications from one run to the next - which matters to agents like
+// Arbitrageur that react to one market's Reset by cancelling orders resting
+// in another market that hasn't reset yet this tick.
+func (s *Simulation) Tick() {
+	for _, f := range s.Firms {
+		f.Act(s.Params)
+	}
+
+	markets := s.markets()
+	names := make([]string, 0, len(markets))
+	for name := range markets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		markets[name].Reset()
+	}
+}
+
+// markets returns every distinct market.Market referenced by Params, keyed
+// by a stable name used for checkpointing.
+func (s *Simulation) markets() map[string]market.Market {
+	out := map[string]market.Market{"labour": s.Params.LabourMarket}
+	for good, info := range s.Params.Goods {
+		out[fmt.Sprintf("good-%d", int(good))] = info.Market
+	}
+	return out
+}
+
+// Checkpoint saves the state of every firm and market to store under keys
+// scoped to tick, so the run can later be restored with Restore.
+func (s *Simulation) Checkpoint(store persistence.Store, tick int) error {
+	for i, f := range s.Firms {
+		data, err := f.MarshalState()
+		if err != nil {
+			return fmt.Errorf("simulation: marshalling firm %d: %w", i, err)
+		}
+		if err := store.Save(firmKey(tick, i), data); err != nil {
+			return fmt.Errorf("simulation: saving firm %d: %w", i, err)
+		}
+	}
+
+	for name, m := range s.markets() {
+		data, err := m.MarshalState()
+		if err != nil {
+			return fmt.Errorf("simulation: marshalling market %s: %w", name, err)
+		}
+		if err := store.Save(marketKey(tick, name), data); err != nil {
+			return fmt.Errorf("simulation: saving market %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Restore loads the state previously saved by Checkpoint for tick, restoring
+// every firm and market in place.
+func (s *Simulation) Restore(store persistence.Store, tick int) error {
+	for i, f := range s.Firms {
+		data, err := store.Load(firmKey(tick, i))
+		if err != nil {
+			return fmt.Errorf("simulation: loading firm %d: %w", i, err)
+		}
+		if err := f.UnmarshalState(data); err != nil {
+			return fmt.Errorf("simulation: restoring firm %d: %w", i, err)
+		}
+	}
+
+	for name, m := range s.markets() {
+		data, err := store.Load(marketKey(tick, name))
+		if err != nil {
+			return fmt.Errorf("simulation: loading market %s: %w", name, err)
+		}
+		if err := m.UnmarshalState(data); err != nil {
+			return fmt.Errorf("simulation: restoring market %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func firmKey(tick, index int) string {
+	return fmt.Sprintf("tick/%d/firm/%d", tick, index)
+}
+
+func marketKey(tick int, name string) string {
+	return fmt.Sprintf("tick/%d/market/%s", tick, name)
+}
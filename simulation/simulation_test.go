@@ -0,0 +1,117 @@
+package simulation
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/robbrit/econerra/agents"
+	"github.com/robbrit/econerra/goods"
+	"github.com/robbrit/econerra/market"
+	"github.com/robbrit/econerra/persistence"
+)
+
+// memStore is an in-memory persistence.Store for testing.
+type memStore struct {
+	data map[string][]byte
+}
+
+func newMemStore() *memStore { return &memStore{data: map[string][]byte{}} }
+
+func (s *memStore) Save(key string, data []byte) error {
+	s.data[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memStore) Load(key string) ([]byte, error) {
+	data, ok := s.data[key]
+	if !ok {
+		return nil, persistence.ErrNotFound
+	}
+	return data, nil
+}
+
+// counterparty is a fake agent that supplies labour and demands wheat every
+// tick, giving the firm under test someone to trade with.
+type counterparty struct{}
+
+func (counterparty) OnFill(goods.Good, market.Side, market.Price, market.Size) {}
+func (counterparty) OnUnfilled(goods.Good, market.Side, market.Size)           {}
+
+func newTestSimulation() (*Simulation, market.Market, market.Market, *counterparty) {
+	labourMarket := market.NewDoubleAuction(goods.Labour)
+	wheatMarket := market.NewDoubleAuction(goods.Wheat)
+
+	params := &agents.Parameters{
+		Goods: map[goods.Good]agents.GoodInfo{
+			goods.Wheat: {Market: wheatMarket, Tech: 2, Scale: 0.5},
+		},
+		LabourMarket: labourMarket,
+		Increment:    market.NewPrice(1),
+	}
+
+	firm := agents.NewFirm(goods.Wheat, market.NewPrice(10), market.NewPrice(20))
+	cp := &counterparty{}
+
+	return New(params, []*agents.Firm{firm}), labourMarket, wheatMarket, cp
+}
+
+// runTick posts counterparty liquidity and then runs one period.
+func runTick(sim *Simulation, labourMarket, wheatMarket market.Market, cp *counterparty) {
+	labourMarket.Post(&market.Order{Price: market.NewPrice(5), Size: market.NewSize(100), Side: market.Sell, Owner: cp})
+	wheatMarket.Post(&market.Order{Price: market.NewPrice(25), Size: market.NewSize(50), Side: market.Buy, Owner: cp})
+	sim.Tick()
+}
+
+// state snapshots every firm and market in sim so trajectories can be
+// compared byte-for-byte.
+func state(t *testing.T, sim *Simulation) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	for _, f := range sim.Firms {
+		data, err := f.MarshalState()
+		if err != nil {
+			t.Fatalf("MarshalState firm: %v", err)
+		}
+		buf.Write(data)
+	}
+	for _, name := range []string{"labour", "good-1"} { // goods.Wheat == 1
+		data, err := sim.markets()[name].MarshalState()
+		if err != nil {
+			t.Fatalf("MarshalState market %s: %v", name, err)
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+func TestSimulationCheckpointRestore(t *testing.T) {
+	sim, labourMarket, wheatMarket, cp := newTestSimulation()
+	store := newMemStore()
+
+	for i := 0; i < 5; i++ {
+		runTick(sim, labourMarket, wheatMarket, cp)
+	}
+
+	if err := sim.Checkpoint(store, 5); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		runTick(sim, labourMarket, wheatMarket, cp)
+	}
+	want := state(t, sim)
+
+	if err := sim.Restore(store, 5); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		runTick(sim, labourMarket, wheatMarket, cp)
+	}
+	got := state(t, sim)
+
+	if !bytes.Equal(want, got) {
+		t.Errorf("trajectory after restore diverged: got %s, want %s", got, want)
+	}
+}